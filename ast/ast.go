@@ -0,0 +1,43 @@
+/*
+Package ast defines the syntax tree produced by the pushdown parser,
+and a handful of encoders for turning that tree into a serialized
+form. The parser builds a tree as it reduces input against a grammar;
+this package only describes the tree's shape and how to write it out,
+so callers can pick XML, JSON, or S-expression output without the
+parser itself caring which one they want.
+*/
+package ast
+
+// Node is satisfied by every node that can appear in a parsed syntax
+// tree: its grammar symbol, the literal token text it carries (if
+// it's a leaf), where it started in the source, and its children.
+type Node interface {
+	Species() string
+	Token() string
+	Pos() int
+	Children() []Node
+}
+
+// Tree is the Node implementation built by the pushdown driver.
+type Tree struct {
+	species  string
+	token    string
+	pos      int
+	children []Node
+}
+
+// NewLeaf creates a childless Tree holding a single consumed terminal.
+func NewLeaf(species, token string, pos int) *Tree {
+	return &Tree{species: species, token: token, pos: pos}
+}
+
+// NewNode creates a Tree for a reduced non-terminal, with the nodes
+// produced while matching its production as children.
+func NewNode(species string, pos int, children ...Node) *Tree {
+	return &Tree{species: species, pos: pos, children: children}
+}
+
+func (t *Tree) Species() string  { return t.species }
+func (t *Tree) Token() string    { return t.token }
+func (t *Tree) Pos() int         { return t.pos }
+func (t *Tree) Children() []Node { return t.children }
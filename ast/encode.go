@@ -0,0 +1,119 @@
+package ast
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncodeXML writes root as nested XML elements named after each
+// node's Species, with leaves rendered as text content. This is the
+// format the package doc originally promised.
+func EncodeXML(w io.Writer, root Node) error {
+	return encodeXML(w, root, 0)
+}
+
+func encodeXML(w io.Writer, n Node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	tag := n.Species()
+	if len(n.Children()) == 0 {
+		if _, err := fmt.Fprintf(w, "%s<%s>", indent, tag); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(w, []byte(n.Token())); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "</%s>\n", tag)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s<%s>\n", indent, tag); err != nil {
+		return err
+	}
+	for _, c := range n.Children() {
+		if err := encodeXML(w, c, depth+1); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s</%s>\n", indent, tag)
+	return err
+}
+
+// EncodeJSON writes root as a JSON object tree:
+// {"species":...,"token":...,"children":[...]}. The "token" and
+// "children" fields are omitted when empty.
+func EncodeJSON(w io.Writer, root Node) error {
+	return encodeJSON(w, root)
+}
+
+func encodeJSON(w io.Writer, n Node) error {
+	if _, err := fmt.Fprintf(w, "{%q:%s", "species", jsonString(n.Species())); err != nil {
+		return err
+	}
+	if n.Token() != "" {
+		if _, err := fmt.Fprintf(w, ",%q:%s", "token", jsonString(n.Token())); err != nil {
+			return err
+		}
+	}
+	if len(n.Children()) > 0 {
+		if _, err := fmt.Fprintf(w, ",%q:[", "children"); err != nil {
+			return err
+		}
+		for i, c := range n.Children() {
+			if i > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			if err := encodeJSON(w, c); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+// jsonString returns s as a properly escaped JSON string literal,
+// quotes included. Unlike fmt's %q, which escapes to Go's own syntax,
+// this matches what package encoding/json itself would write, which
+// matters once s can hold arbitrary matched input (e.g. a Regex or
+// TermRange terminal's lexeme) rather than just grammar-author text.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// EncodeSExpr writes root as a parenthesized S-expression, e.g.
+// (varA (Terminal "0") (varA (Terminal "2")) (Terminal "1")).
+func EncodeSExpr(w io.Writer, root Node) error {
+	return encodeSExpr(w, root)
+}
+
+func encodeSExpr(w io.Writer, n Node) error {
+	if len(n.Children()) == 0 {
+		if n.Token() == "" {
+			_, err := fmt.Fprintf(w, "(%s)", n.Species())
+			return err
+		}
+		_, err := fmt.Fprintf(w, "(%s %q)", n.Species(), n.Token())
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "(%s", n.Species()); err != nil {
+		return err
+	}
+	for _, c := range n.Children() {
+		if _, err := io.WriteString(w, " "); err != nil {
+			return err
+		}
+		if err := encodeSExpr(w, c); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, ")")
+	return err
+}
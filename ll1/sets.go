@@ -0,0 +1,213 @@
+/*
+Package ll1 computes FIRST and FOLLOW sets for a pushdown.Grammar and
+builds an LL(1) parse table from them. The table lets a Parser (see
+package pushdown) pick the right alternative of a non-terminal from a
+single symbol of lookahead, instead of trying each alternative in turn
+and backtracking on failure.
+
+The FIRST/FOLLOW construction is the standard fixpoint: FIRST(a) = {a}
+for a terminal a; for a sequence X1..Xn, FIRST includes FIRST(X1), and
+if X1 is nullable, continues into FIRST(X2), and so on. FOLLOW(Start)
+always contains the end-of-input marker; for a production A -> αBβ,
+FIRST(β) (minus epsilon) is added to FOLLOW(B), and if β is nullable
+(or empty), FOLLOW(A) is added to FOLLOW(B) too. Both are iterated to a
+fixpoint.
+*/
+package ll1
+
+import "github.com/fractalbach/pushdown/pushdown"
+
+// endOfInput is the pseudo-terminal added to FOLLOW(g.Start). It is
+// the empty string, which no lexeme a Scanner produces ever is;
+// pushdown.Term does not reject empty text, so a grammar with an empty
+// string literal shares this key with it.
+const endOfInput = ""
+
+// symSeq returns sym's immediate sequence of sub-symbols: a Concat's
+// list, or sym itself as a single-element sequence for anything else.
+func symSeq(sym pushdown.Symbol) []pushdown.Symbol {
+	if sym.Kind() == pushdown.Concat {
+		return sym.List()
+	}
+	return []pushdown.Symbol{sym}
+}
+
+// alternatives returns sym's top-level alternatives: a Union's list,
+// or sym itself as the grammar's only alternative.
+func alternatives(sym pushdown.Symbol) []pushdown.Symbol {
+	if sym.Kind() == pushdown.Union {
+		return sym.List()
+	}
+	return []pushdown.Symbol{sym}
+}
+
+// computeNullable returns the set of non-terminals that can derive
+// the empty string.
+func computeNullable(g pushdown.Grammar) map[string]bool {
+	nullable := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.Names() {
+			if nullable[name] {
+				continue
+			}
+			body, _ := g.Symbol(name)
+			if symbolNullable(body, nullable) {
+				nullable[name] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
+}
+
+func symbolNullable(sym pushdown.Symbol, nullable map[string]bool) bool {
+	switch sym.Kind() {
+	case pushdown.Terminal:
+		return false
+	case pushdown.Variable:
+		return nullable[sym.Data()]
+	case pushdown.Concat:
+		for _, c := range sym.List() {
+			if !symbolNullable(c, nullable) {
+				return false
+			}
+		}
+		return true
+	case pushdown.Union:
+		for _, c := range sym.List() {
+			if symbolNullable(c, nullable) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func sequenceNullable(seq []pushdown.Symbol, nullable map[string]bool) bool {
+	for _, sym := range seq {
+		if !symbolNullable(sym, nullable) {
+			return false
+		}
+	}
+	return true
+}
+
+// computeFirst returns FIRST(name) for every non-terminal name: the
+// set of terminals that can begin a string it derives.
+func computeFirst(g pushdown.Grammar, nullable map[string]bool) map[string]map[string]bool {
+	first := map[string]map[string]bool{}
+	for _, name := range g.Names() {
+		first[name] = map[string]bool{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.Names() {
+			body, _ := g.Symbol(name)
+			before := len(first[name])
+			collectFirst(symSeq(body), nullable, first, first[name])
+			if len(first[name]) != before {
+				changed = true
+			}
+		}
+	}
+	return first
+}
+
+// collectFirst adds every terminal that can begin seq to into,
+// stopping at the first symbol of seq that isn't nullable.
+func collectFirst(seq []pushdown.Symbol, nullable map[string]bool, first map[string]map[string]bool, into map[string]bool) {
+	for _, sym := range seq {
+		switch sym.Kind() {
+		case pushdown.Terminal:
+			into[sym.Data()] = true
+		case pushdown.Variable:
+			for k := range first[sym.Data()] {
+				into[k] = true
+			}
+		case pushdown.Concat:
+			collectFirst(sym.List(), nullable, first, into)
+		case pushdown.Union:
+			for _, alt := range sym.List() {
+				collectFirst([]pushdown.Symbol{alt}, nullable, first, into)
+			}
+		}
+		if !symbolNullable(sym, nullable) {
+			return
+		}
+	}
+}
+
+// computeFollow returns FOLLOW(name) for every non-terminal name: the
+// set of terminals (plus endOfInput for g.Start) that can immediately
+// follow it in some derivation.
+func computeFollow(g pushdown.Grammar, nullable map[string]bool, first map[string]map[string]bool) map[string]map[string]bool {
+	follow := map[string]map[string]bool{}
+	for _, name := range g.Names() {
+		follow[name] = map[string]bool{}
+	}
+	follow[g.Start][endOfInput] = true
+
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.Names() {
+			body, _ := g.Symbol(name)
+			if followOfSeq(name, symSeq(body), nullable, first, follow) {
+				changed = true
+			}
+		}
+	}
+	return follow
+}
+
+// followOfSeq walks seq — the right-hand side of production owner, or
+// a suffix of it reached through recursion — updating FOLLOW for every
+// Variable it contains.
+func followOfSeq(owner string, seq []pushdown.Symbol, nullable map[string]bool, first map[string]map[string]bool, follow map[string]map[string]bool) bool {
+	changed := false
+	for i, sym := range seq {
+		if followOfOccurrence(owner, sym, seq[i+1:], nullable, first, follow) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// followOfOccurrence updates FOLLOW for one occurrence of sym, given
+// rest, the symbols of owner's production that follow it.
+func followOfOccurrence(owner string, sym pushdown.Symbol, rest []pushdown.Symbol, nullable map[string]bool, first map[string]map[string]bool, follow map[string]map[string]bool) bool {
+	changed := false
+	switch sym.Kind() {
+	case pushdown.Variable:
+		name := sym.Data()
+		firstRest := map[string]bool{}
+		collectFirst(rest, nullable, first, firstRest)
+		for k := range firstRest {
+			if !follow[name][k] {
+				follow[name][k] = true
+				changed = true
+			}
+		}
+		if sequenceNullable(rest, nullable) {
+			for k := range follow[owner] {
+				if !follow[name][k] {
+					follow[name][k] = true
+					changed = true
+				}
+			}
+		}
+	case pushdown.Union:
+		for _, alt := range sym.List() {
+			if followOfOccurrence(owner, alt, rest, nullable, first, follow) {
+				changed = true
+			}
+		}
+	case pushdown.Concat:
+		inner := append(append([]pushdown.Symbol{}, sym.List()...), rest...)
+		if followOfSeq(owner, inner, nullable, first, follow) {
+			changed = true
+		}
+	}
+	return changed
+}
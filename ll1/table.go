@@ -0,0 +1,206 @@
+package ll1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fractalbach/pushdown/pushdown"
+)
+
+// Table is an LL(1) parse table: for a non-terminal and a lookahead
+// terminal, it names the single alternative to expand. It implements
+// pushdown.Table, so a *Parser can use it in place of trial-and-error.
+type Table struct {
+	cell   map[string]map[string]pushdown.Symbol
+	follow map[string]map[string]bool
+}
+
+// Lookup returns the alternative to expand non-terminal name when the
+// next input symbol is term, or ok=false if the grammar has none.
+func (t *Table) Lookup(name, term string) (pushdown.Symbol, bool) {
+	row, ok := t.cell[name]
+	if !ok {
+		return nil, false
+	}
+	alt, ok := row[term]
+	return alt, ok
+}
+
+// InFollow reports whether term is in FOLLOW(name), i.e. whether it
+// can legitimately appear immediately after non-terminal name. Parser
+// uses this during panic-mode error recovery to decide whether a
+// lookahead is a safe point to resume at.
+func (t *Table) InFollow(name, term string) bool {
+	return t.follow[name][term]
+}
+
+func (t *Table) set(name, term string, alt pushdown.Symbol) error {
+	row, ok := t.cell[name]
+	if !ok {
+		row = map[string]pushdown.Symbol{}
+		t.cell[name] = row
+	}
+	if existing, ok := row[term]; ok && existing != alt {
+		return &ConflictError{NonTerminal: name, Lookahead: term, Productions: []pushdown.Symbol{existing, alt}}
+	}
+	row[term] = alt
+	return nil
+}
+
+// ConflictError reports that two or more alternatives of a
+// non-terminal would populate the same parse-table cell, meaning the
+// grammar is not LL(1).
+type ConflictError struct {
+	NonTerminal string
+	Lookahead   string
+	Productions []pushdown.Symbol
+}
+
+func (e *ConflictError) Error() string {
+	lookahead := e.Lookahead
+	if lookahead == endOfInput {
+		lookahead = "<end of input>"
+	}
+	return fmt.Sprintf("ll1: grammar is not LL(1): %d productions of %q conflict on lookahead %q",
+		len(e.Productions), e.NonTerminal, lookahead)
+}
+
+// LeftRecursionError reports that Cycle is a chain of non-terminals
+// each of which can start with the next, looping back to the first —
+// direct or indirect left recursion, which an LL(1) table can't
+// express.
+type LeftRecursionError struct {
+	Cycle []string
+}
+
+func (e *LeftRecursionError) Error() string {
+	return fmt.Sprintf("ll1: left recursion detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// BuildTable computes FIRST/FOLLOW sets for g and constructs its
+// LL(1) parse table. It returns a *LeftRecursionError if g contains
+// direct or indirect left recursion, or a *ConflictError if two
+// alternatives of the same non-terminal would populate the same
+// table cell.
+func BuildTable(g pushdown.Grammar) (*Table, error) {
+	if cycle := findLeftRecursion(g); cycle != nil {
+		return nil, &LeftRecursionError{Cycle: cycle}
+	}
+
+	nullable := computeNullable(g)
+	first := computeFirst(g, nullable)
+	follow := computeFollow(g, nullable, first)
+
+	t := &Table{cell: map[string]map[string]pushdown.Symbol{}, follow: follow}
+	for _, name := range g.Names() {
+		body, _ := g.Symbol(name)
+		for _, alt := range alternatives(body) {
+			seq := symSeq(alt)
+
+			terms := map[string]bool{}
+			collectFirst(seq, nullable, first, terms)
+			for term := range terms {
+				if err := t.set(name, term, alt); err != nil {
+					return nil, err
+				}
+			}
+
+			if sequenceNullable(seq, nullable) {
+				for term := range follow[name] {
+					if err := t.set(name, term, alt); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+// findLeftRecursion looks for a cycle of non-terminals A1, A2, ..., An
+// where each Ai can appear as the leftmost symbol of some alternative
+// of Ai-1 (accounting for nullable prefixes), and returns the cycle if
+// one exists.
+func findLeftRecursion(g pushdown.Grammar) []string {
+	nullable := computeNullable(g)
+	adj := map[string][]string{}
+	for _, name := range g.Names() {
+		body, _ := g.Symbol(name)
+		for _, alt := range alternatives(body) {
+			leftNonterminals(symSeq(alt), nullable, func(b string) {
+				adj[name] = append(adj[name], b)
+			})
+		}
+	}
+
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := map[string]int{}
+	var path []string
+	var cycle []string
+
+	var visit func(n string) bool
+	visit = func(n string) bool {
+		color[n] = gray
+		path = append(path, n)
+		for _, m := range adj[n] {
+			switch color[m] {
+			case gray:
+				start := indexOf(path, m)
+				cycle = append(append([]string{}, path[start:]...), m)
+				return true
+			case white:
+				if visit(m) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		color[n] = black
+		return false
+	}
+
+	names := g.Names()
+	sort.Strings(names)
+	for _, name := range names {
+		if color[name] == white {
+			if visit(name) {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// leftNonterminals calls yield for every non-terminal that can appear
+// as the leftmost symbol of seq, accounting for nullable prefixes.
+func leftNonterminals(seq []pushdown.Symbol, nullable map[string]bool, yield func(string)) {
+	for _, sym := range seq {
+		switch sym.Kind() {
+		case pushdown.Variable:
+			yield(sym.Data())
+		case pushdown.Concat:
+			leftNonterminals(sym.List(), nullable, yield)
+		case pushdown.Union:
+			for _, alt := range sym.List() {
+				leftNonterminals([]pushdown.Symbol{alt}, nullable, yield)
+			}
+		}
+		if !symbolNullable(sym, nullable) {
+			return
+		}
+	}
+}
+
+func indexOf(s []string, v string) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+package ll1
+
+import (
+	"testing"
+
+	"github.com/fractalbach/pushdown/pushdown"
+)
+
+// exprGrammar is the textbook non-left-recursive expression grammar:
+//
+//	E  = T E' ;
+//	E' = [ "+", E ] ;
+//	T  = "id" ;
+func exprGrammar(t *testing.T) pushdown.Grammar {
+	t.Helper()
+	g, err := pushdown.ParseEBNF([]byte(`
+		E  = T, E' ;
+		E' = [ "+", E ] ;
+		T  = "id" ;
+	`), "E")
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+	return g
+}
+
+func TestBuildTableLL1Grammar(t *testing.T) {
+	g := exprGrammar(t)
+	table, err := BuildTable(g)
+	if err != nil {
+		t.Fatalf("BuildTable: %v", err)
+	}
+
+	if _, ok := table.Lookup("E", "id"); !ok {
+		t.Error(`Lookup("E", "id") = false, want true`)
+	}
+	if _, ok := table.Lookup("E", "+"); ok {
+		t.Error(`Lookup("E", "+") = true, want false ("+" isn't in FIRST(E))`)
+	}
+	if _, ok := table.Lookup("E'", "+"); !ok {
+		t.Error(`Lookup("E'", "+") = false, want true`)
+	}
+	if _, ok := table.Lookup("E'", endOfInput); !ok {
+		t.Error(`Lookup("E'", endOfInput) = false, want true (the empty alternative)`)
+	}
+
+	if !table.InFollow("E", endOfInput) {
+		t.Error(`InFollow("E", endOfInput) = false, want true`)
+	}
+	if table.InFollow("E", "id") {
+		t.Error(`InFollow("E", "id") = true, want false`)
+	}
+}
+
+func TestBuildTableConflict(t *testing.T) {
+	g, err := pushdown.ParseEBNF([]byte(`
+		A = "x" | "x", "y" ;
+	`), "A")
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	_, err = BuildTable(g)
+	var conflict *ConflictError
+	if err == nil {
+		t.Fatal("BuildTable: got nil error, want *ConflictError")
+	}
+	if ce, ok := err.(*ConflictError); ok {
+		conflict = ce
+	} else {
+		t.Fatalf("BuildTable: error %v has type %T, want *ConflictError", err, err)
+	}
+	if conflict.NonTerminal != "A" || conflict.Lookahead != "x" {
+		t.Errorf("ConflictError = %+v, want NonTerminal %q, Lookahead %q", conflict, "A", "x")
+	}
+}
+
+func TestBuildTableLeftRecursion(t *testing.T) {
+	g, err := pushdown.ParseEBNF([]byte(`
+		A = A, "x" | "y" ;
+	`), "A")
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	_, err = BuildTable(g)
+	if err == nil {
+		t.Fatal("BuildTable: got nil error, want *LeftRecursionError")
+	}
+	lre, ok := err.(*LeftRecursionError)
+	if !ok {
+		t.Fatalf("BuildTable: error %v has type %T, want *LeftRecursionError", err, err)
+	}
+	if len(lre.Cycle) == 0 || lre.Cycle[0] != "A" {
+		t.Errorf("LeftRecursionError.Cycle = %v, want to start with %q", lre.Cycle, "A")
+	}
+}
@@ -0,0 +1,529 @@
+package pushdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Grammar is a parsed EBNF grammar: a named set of productions plus
+// the symbol where parsing begins. Productions still use the *token
+// representation defined in token.go (Term, And, Or, Var), so a Grammar
+// built by ParseEBNF plugs straight into process/parseToken.
+type Grammar struct {
+	Start       string
+	Productions map[string]*token
+
+	// Unreachable lists productions that can never be expanded
+	// starting from Start. It is informational, not an error.
+	Unreachable []string
+
+	// sync holds, per non-terminal, extra tokens that designate a
+	// safe point to resume parsing during panic-mode error
+	// recovery (see Sync).
+	sync map[string]map[string]bool
+}
+
+// Sync designates terms as synchronizing tokens for non-terminal
+// name: seeing one of them as the lookahead is grounds for a Parser to
+// resume parsing at name during panic-mode error recovery, even if it
+// isn't in name's FOLLOW set — e.g. ';' or '}' closing an enclosing
+// construct that FOLLOW sets alone might not capture.
+func (g *Grammar) Sync(name string, terms ...string) {
+	if g.sync == nil {
+		g.sync = map[string]map[string]bool{}
+	}
+	set, ok := g.sync[name]
+	if !ok {
+		set = map[string]bool{}
+		g.sync[name] = set
+	}
+	for _, t := range terms {
+		set[t] = true
+	}
+}
+
+func (g Grammar) inSync(name, term string) bool {
+	return g.sync[name][term]
+}
+
+// Symbol returns the production body for name as a read-only Symbol,
+// for analysis packages (such as ll1) that don't depend on pushdown's
+// internal token representation.
+func (g Grammar) Symbol(name string) (Symbol, bool) {
+	t, ok := g.Productions[name]
+	return t, ok
+}
+
+// Names returns the names of every production in the grammar, in no
+// particular order.
+func (g Grammar) Names() []string {
+	names := make([]string, 0, len(g.Productions))
+	for name := range g.Productions {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ParseEBNF tokenizes and parses src as an EBNF grammar using the
+// productions:
+//
+//	Production  = name "=" Expression ( ";" | "." ) .
+//	Expression  = Alternative { "|" Alternative } .
+//	Alternative = Term { "," Term } .
+//	Term        = name | token [ "..." token ] | Special | Group | Option | Repetition .
+//	Special     = "?" regexp pattern "?" .
+//	Group       = "(" Expression ")" .
+//	Option      = "[" Expression "]" .
+//	Repetition  = "{" Expression "}" .
+//
+// Special is ISO 14977's "special sequence", repurposed here to build
+// a Regex terminal from the enclosed pattern text instead of an exact
+// token or a TermRange.
+//
+// start names the production where parsing begins. If omitted, it
+// defaults to "$" for compatibility with this package's original
+// hand-written grammars, falling back to the first production parsed
+// if no production is named "$".
+//
+// Once the grammar is built, ParseEBNF checks that every non-terminal
+// referenced anywhere in it has a matching production, returning an
+// error if not. Productions that exist but can never be reached from
+// start are reported separately via Grammar.Unreachable.
+func ParseEBNF(src []byte, start ...string) (Grammar, error) {
+	p := &ebnfParser{
+		lex:  newEBNFLexer(src),
+		gram: Grammar{Productions: map[string]*token{}},
+	}
+	if err := p.advance(); err != nil {
+		return Grammar{}, err
+	}
+
+	first := ""
+	for p.tok.kind != ebnfEOF {
+		name, body, err := p.parseProduction()
+		if err != nil {
+			return Grammar{}, err
+		}
+		if first == "" {
+			first = name
+		}
+		p.gram.Productions[name] = body
+	}
+
+	p.gram.Start = "$"
+	if len(start) > 0 && start[0] != "" {
+		p.gram.Start = start[0]
+	} else if _, ok := p.gram.Productions[p.gram.Start]; !ok {
+		p.gram.Start = first
+	}
+
+	if err := p.gram.checkUndefined(); err != nil {
+		return Grammar{}, err
+	}
+	p.gram.Unreachable = p.gram.findUnreachable()
+	return p.gram, nil
+}
+
+// checkUndefined verifies that every Var reference used anywhere in
+// the grammar names a production that actually exists.
+func (g *Grammar) checkUndefined() error {
+	var missing []string
+	seen := map[string]bool{}
+	var walk func(t *token)
+	walk = func(t *token) {
+		if t == nil {
+			return
+		}
+		switch t.kind {
+		case Variable:
+			if _, ok := g.Productions[t.data]; !ok && !seen[t.data] {
+				seen[t.data] = true
+				missing = append(missing, t.data)
+			}
+		case Concat, Union:
+			for _, c := range t.list {
+				walk(c)
+			}
+		}
+	}
+	for _, body := range g.Productions {
+		walk(body)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("ebnf: undefined non-terminal(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// findUnreachable returns, in sorted order, the production names that
+// can never be reached by expanding the grammar from g.Start.
+func (g *Grammar) findUnreachable() []string {
+	reached := map[string]bool{}
+	var walk func(name string)
+	walk = func(name string) {
+		if reached[name] {
+			return
+		}
+		reached[name] = true
+		var visit func(t *token)
+		visit = func(t *token) {
+			if t == nil {
+				return
+			}
+			switch t.kind {
+			case Variable:
+				walk(t.data)
+			case Concat, Union:
+				for _, c := range t.list {
+					visit(c)
+				}
+			}
+		}
+		visit(g.Productions[name])
+	}
+	walk(g.Start)
+
+	var unreachable []string
+	for name := range g.Productions {
+		if !reached[name] {
+			unreachable = append(unreachable, name)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable
+}
+
+// terminals returns every Terminal token reachable from g's
+// productions, for a Scanner to match against raw input.
+func (g *Grammar) terminals() []*token {
+	seen := map[*token]bool{}
+	var out []*token
+	var walk func(t *token)
+	walk = func(t *token) {
+		if t == nil || seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t.kind {
+		case Terminal:
+			out = append(out, t)
+		case Concat, Union:
+			for _, c := range t.list {
+				walk(c)
+			}
+		}
+	}
+	for _, body := range g.Productions {
+		walk(body)
+	}
+	return out
+}
+
+// ebnfTokKind identifies the lexical class of a single EBNF token.
+type ebnfTokKind int
+
+const (
+	ebnfIdent ebnfTokKind = iota
+	ebnfString
+	ebnfSpecial
+	ebnfPunct
+	ebnfEOF
+)
+
+type ebnfTok struct {
+	kind ebnfTokKind
+	text string
+	pos  int
+}
+
+// ebnfLexer turns EBNF source text into a stream of ebnfTok values. It
+// skips whitespace and "(* ... *)" comments between tokens.
+type ebnfLexer struct {
+	src []byte
+	pos int
+}
+
+func newEBNFLexer(src []byte) *ebnfLexer {
+	return &ebnfLexer{src: src}
+}
+
+func (l *ebnfLexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '(' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '*' {
+			l.pos += 2
+			for l.pos+1 < len(l.src) && !(l.src[l.pos] == '*' && l.src[l.pos+1] == ')') {
+				l.pos++
+			}
+			l.pos += 2
+			continue
+		}
+		if unicode.IsSpace(rune(c)) {
+			l.pos++
+			continue
+		}
+		break
+	}
+}
+
+// next returns the next token in the stream, or an ebnfEOF token once
+// the input is exhausted.
+func (l *ebnfLexer) next() (ebnfTok, error) {
+	l.skipSpaceAndComments()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return ebnfTok{kind: ebnfEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+
+	switch {
+	case c == '\'' || c == '"':
+		quote := c
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != quote {
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return ebnfTok{}, fmt.Errorf("ebnf: unterminated string starting at %d", start)
+		}
+		text := string(l.src[start+1 : l.pos])
+		l.pos++
+		return ebnfTok{kind: ebnfString, text: text, pos: start}, nil
+
+	case c == '?':
+		// ISO 14977's "special sequence": arbitrary text between a
+		// pair of '?', meaning whatever the grammar author and the
+		// tool reading the grammar agree it means. Here it names a
+		// regexp pattern (see Regex), matched against however much of
+		// the input satisfies it instead of a single literal or
+		// character range. Whitespace just inside the '?' delimiters
+		// is trimmed, so "? [a-z]+ ?" names the pattern "[a-z]+".
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.src) && l.src[l.pos] != '?' {
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return ebnfTok{}, fmt.Errorf("ebnf: unterminated special sequence starting at %d", start)
+		}
+		text := strings.TrimSpace(string(l.src[start:l.pos]))
+		l.pos++
+		return ebnfTok{kind: ebnfSpecial, text: text, pos: start}, nil
+
+	case unicode.IsLetter(rune(c)) || c == '_' || c == '$':
+		// '$' is allowed in identifiers, not just letters/digits/'_',
+		// so a grammar can name its start production "$" (the
+		// default ParseEBNF falls back to, for compatibility with
+		// this package's original hand-written grammars) instead of
+		// being unable to spell it at all. A trailing "'" is allowed
+		// too (but only once an identifier is already underway, since
+		// a leading "'" is still a string-quote delimiter, handled
+		// above), for the common math-notation convention of naming a
+		// derived production E' from E.
+		for l.pos < len(l.src) && (unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos])) || l.src[l.pos] == '_' || l.src[l.pos] == '$' || l.src[l.pos] == '\'') {
+			l.pos++
+		}
+		return ebnfTok{kind: ebnfIdent, text: string(l.src[start:l.pos]), pos: start}, nil
+
+	case c == '.' && l.pos+2 < len(l.src) && l.src[l.pos+1] == '.' && l.src[l.pos+2] == '.':
+		l.pos += 3
+		return ebnfTok{kind: ebnfPunct, text: "...", pos: start}, nil
+
+	case strings.ContainsRune("=,|;()[]{}.", rune(c)):
+		l.pos++
+		return ebnfTok{kind: ebnfPunct, text: string(c), pos: start}, nil
+
+	default:
+		return ebnfTok{}, fmt.Errorf("ebnf: unexpected character %q at %d", c, start)
+	}
+}
+
+// ebnfParser builds a Grammar by recursive descent over the tokens
+// produced by an ebnfLexer.
+type ebnfParser struct {
+	lex       *ebnfLexer
+	tok       ebnfTok
+	gram      Grammar
+	synthetic int
+}
+
+func (p *ebnfParser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *ebnfParser) expectPunct(s string) error {
+	if p.tok.kind != ebnfPunct || p.tok.text != s {
+		return fmt.Errorf("ebnf: expected %q at %d, got %q", s, p.tok.pos, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *ebnfParser) parseProduction() (string, *token, error) {
+	if p.tok.kind != ebnfIdent {
+		return "", nil, fmt.Errorf("ebnf: expected production name at %d, got %q", p.tok.pos, p.tok.text)
+	}
+	name := p.tok.text
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+	if err := p.expectPunct("="); err != nil {
+		return "", nil, err
+	}
+	expr, err := p.parseExpression()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.tok.kind != ebnfPunct || (p.tok.text != ";" && p.tok.text != ".") {
+		return "", nil, fmt.Errorf("ebnf: expected ';' terminating production %q at %d", name, p.tok.pos)
+	}
+	if err := p.advance(); err != nil {
+		return "", nil, err
+	}
+	return name, expr, nil
+}
+
+func (p *ebnfParser) parseExpression() (*token, error) {
+	first, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	alts := []*token{first}
+	for p.tok.kind == ebnfPunct && p.tok.text == "|" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		next, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, next)
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return Or(alts...), nil
+}
+
+func (p *ebnfParser) parseAlternative() (*token, error) {
+	first, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	terms := []*token{first}
+	for p.tok.kind == ebnfPunct && p.tok.text == "," {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		next, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, next)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return And(terms...), nil
+}
+
+func (p *ebnfParser) parseTerm() (*token, error) {
+	switch {
+	case p.tok.kind == ebnfIdent:
+		name := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return Var(name), nil
+
+	case p.tok.kind == ebnfString:
+		lo := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == ebnfPunct && p.tok.text == "..." {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != ebnfString {
+				return nil, fmt.Errorf("ebnf: expected terminal after '...' at %d", p.tok.pos)
+			}
+			hi := p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return TermRange(lo, hi), nil
+		}
+		return Term(lo), nil
+
+	case p.tok.kind == ebnfSpecial:
+		pattern := p.tok.text
+		pos := p.tok.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		t, err := regexTerm(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ebnf: invalid regex special sequence %q at %d: %v", pattern, pos, err)
+		}
+		return t, nil
+
+	case p.tok.kind == ebnfPunct && p.tok.text == "(":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+
+	case p.tok.kind == ebnfPunct && p.tok.text == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return nil, err
+		}
+		return Or(expr, And()), nil
+
+	case p.tok.kind == ebnfPunct && p.tok.text == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("}"); err != nil {
+			return nil, err
+		}
+		name := p.newSyntheticName()
+		p.gram.Productions[name] = Or(And(expr, Var(name)), And())
+		return Var(name), nil
+
+	default:
+		return nil, fmt.Errorf("ebnf: unexpected token %q at %d", p.tok.text, p.tok.pos)
+	}
+}
+
+// newSyntheticName returns a fresh production name for the recursive
+// production generated to desugar a "{ ... }" repetition.
+func (p *ebnfParser) newSyntheticName() string {
+	p.synthetic++
+	return fmt.Sprintf("$rep%d", p.synthetic)
+}
@@ -0,0 +1,41 @@
+package pushdown_test
+
+import (
+	"testing"
+
+	"github.com/fractalbach/pushdown/pushdown"
+)
+
+// TestParseEBNFRegexSpecialSequence exercises the "? ... ?" special
+// sequence, the only way a grammar loaded from text (as opposed to
+// one built by hand with Term/And/Or/Var/Regex) can reach a Regex
+// terminal.
+func TestParseEBNFRegexSpecialSequence(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF([]byte(`
+		$ = ? [a-z]+ ? ;
+	`))
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("hello")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	root, err := p.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if root == nil {
+		t.Fatal("Finish returned a nil root for valid input")
+	}
+}
+
+func TestParseEBNFRegexSpecialSequenceInvalidPattern(t *testing.T) {
+	_, err := pushdown.ParseEBNF([]byte(`
+		$ = ? ( ? ;
+	`))
+	if err == nil {
+		t.Fatal("ParseEBNF: got nil error for an unbalanced regex pattern, want an error")
+	}
+}
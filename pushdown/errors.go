@@ -0,0 +1,45 @@
+package pushdown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is a single error recovered from during a parse, along
+// with the position where it was detected.
+type ParseError struct {
+	Pos Position
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ParseErrorList collects every error a Parser recovers from over the
+// course of a parse, in the order they were encountered, so one bad
+// input can yield several useful diagnostics instead of just the
+// first one.
+type ParseErrorList struct {
+	Errors []*ParseError
+}
+
+func (l *ParseErrorList) add(pos Position, err error) {
+	l.Errors = append(l.Errors, &ParseError{Pos: pos, Err: err})
+}
+
+func (l *ParseErrorList) Error() string {
+	switch len(l.Errors) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l.Errors[0].Error()
+	}
+	parts := make([]string, len(l.Errors))
+	for i, e := range l.Errors {
+		parts[i] = e.Error()
+	}
+	return fmt.Sprintf("%d parse errors:\n%s", len(l.Errors), strings.Join(parts, "\n"))
+}
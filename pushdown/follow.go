@@ -0,0 +1,187 @@
+package pushdown
+
+// followSets computes FOLLOW(name) for every non-terminal reachable
+// in g: the set of terminals that can immediately follow it in some
+// derivation. Parser.recover consults it directly during panic-mode
+// error recovery, so recovery works for any grammar, not only the
+// LL(1) ones a Table (see package ll1) happens to cover.
+//
+// This is the same FIRST/FOLLOW fixpoint package ll1 computes to
+// build its parse table, evaluated directly over *token instead of
+// through the Symbol interface: ll1 depends on Symbol to stay
+// decoupled from pushdown's internal representation, but pushdown
+// itself has no such reason to go through it. Unlike ll1, it does not
+// seed FOLLOW(g.Start) with an end-of-input marker.
+func (g *Grammar) followSets() map[string]map[string]bool {
+	nullable := g.nullableSet()
+	first := g.firstSets(nullable)
+
+	follow := map[string]map[string]bool{}
+	for name := range g.Productions {
+		follow[name] = map[string]bool{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, body := range g.Productions {
+			if followOfSeq(name, seqOf(body), nullable, first, follow) {
+				changed = true
+			}
+		}
+	}
+	return follow
+}
+
+// nullableSet returns the set of non-terminals in g that can derive
+// the empty string.
+func (g *Grammar) nullableSet() map[string]bool {
+	nullable := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for name, body := range g.Productions {
+			if nullable[name] {
+				continue
+			}
+			if tokenNullable(body, nullable) {
+				nullable[name] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
+}
+
+func tokenNullable(t *token, nullable map[string]bool) bool {
+	switch t.kind {
+	case Variable:
+		return nullable[t.data]
+	case Concat:
+		for _, c := range t.list {
+			if !tokenNullable(c, nullable) {
+				return false
+			}
+		}
+		return true
+	case Union:
+		for _, c := range t.list {
+			if tokenNullable(c, nullable) {
+				return true
+			}
+		}
+		return false
+	default: // Terminal
+		return false
+	}
+}
+
+func seqNullable(seq []*token, nullable map[string]bool) bool {
+	for _, t := range seq {
+		if !tokenNullable(t, nullable) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstSets returns FIRST(name) for every non-terminal: the set of
+// terminals that can begin a string it derives.
+func (g *Grammar) firstSets(nullable map[string]bool) map[string]map[string]bool {
+	first := map[string]map[string]bool{}
+	for name := range g.Productions {
+		first[name] = map[string]bool{}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, body := range g.Productions {
+			before := len(first[name])
+			collectFirst(seqOf(body), nullable, first, first[name])
+			if len(first[name]) != before {
+				changed = true
+			}
+		}
+	}
+	return first
+}
+
+// seqOf returns t's immediate sequence of sub-symbols: a Concat's
+// list, or t itself as a single-element sequence for anything else.
+func seqOf(t *token) []*token {
+	if t.kind == Concat {
+		return t.list
+	}
+	return []*token{t}
+}
+
+// collectFirst adds every terminal that can begin seq to into,
+// stopping at the first symbol of seq that isn't nullable.
+func collectFirst(seq []*token, nullable map[string]bool, first map[string]map[string]bool, into map[string]bool) {
+	for _, t := range seq {
+		switch t.kind {
+		case Terminal:
+			into[t.data] = true
+		case Variable:
+			for k := range first[t.data] {
+				into[k] = true
+			}
+		case Concat:
+			collectFirst(t.list, nullable, first, into)
+		case Union:
+			for _, alt := range t.list {
+				collectFirst([]*token{alt}, nullable, first, into)
+			}
+		}
+		if !tokenNullable(t, nullable) {
+			return
+		}
+	}
+}
+
+// followOfSeq walks seq — the right-hand side of production owner, or
+// a suffix of it reached through recursion — updating FOLLOW for
+// every Variable it contains.
+func followOfSeq(owner string, seq []*token, nullable map[string]bool, first map[string]map[string]bool, follow map[string]map[string]bool) bool {
+	changed := false
+	for i, t := range seq {
+		if followOfOccurrence(owner, t, seq[i+1:], nullable, first, follow) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// followOfOccurrence updates FOLLOW for one occurrence of t, given
+// rest, the symbols of owner's production that follow it.
+func followOfOccurrence(owner string, t *token, rest []*token, nullable map[string]bool, first map[string]map[string]bool, follow map[string]map[string]bool) bool {
+	changed := false
+	switch t.kind {
+	case Variable:
+		name := t.data
+		firstRest := map[string]bool{}
+		collectFirst(rest, nullable, first, firstRest)
+		for k := range firstRest {
+			if !follow[name][k] {
+				follow[name][k] = true
+				changed = true
+			}
+		}
+		if seqNullable(rest, nullable) {
+			for k := range follow[owner] {
+				if !follow[name][k] {
+					follow[name][k] = true
+					changed = true
+				}
+			}
+		}
+	case Union:
+		for _, alt := range t.list {
+			if followOfOccurrence(owner, alt, rest, nullable, first, follow) {
+				changed = true
+			}
+		}
+	case Concat:
+		inner := append(append([]*token{}, t.list...), rest...)
+		if followOfSeq(owner, inner, nullable, first, follow) {
+			changed = true
+		}
+	}
+	return changed
+}
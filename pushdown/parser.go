@@ -0,0 +1,438 @@
+package pushdown
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/fractalbach/pushdown/ast"
+)
+
+// Position locates a single rune of input by line and column, both
+// 1-based, for use in error messages.
+type Position struct {
+	Line, Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// astFrame accumulates the children of a non-terminal that is
+// currently being expanded, from the moment it's pushed until its
+// matching EndVariable marker is popped.
+type astFrame struct {
+	species  string
+	offset   int
+	children []ast.Node
+}
+
+// Table is satisfied by an LL(1) parse table (see package ll1). Given
+// a non-terminal and a lookahead terminal, Lookup resolves which
+// alternative of that non-terminal to expand, without trying
+// alternatives one at a time and backtracking on failure; InFollow
+// reports whether a terminal can legitimately follow a non-terminal,
+// consulted alongside Parser's own grammar-derived FOLLOW sets during
+// panic-mode error recovery. Parser consults Lookup, if a Table is
+// set, only at the point where it expands a Variable; a Union nested
+// inside an alternative (e.g. from a grouped "(a|b)") still falls back
+// to parseUnion's trial-and-error.
+type Table interface {
+	Lookup(name, term string) (Symbol, bool)
+	InFollow(name, term string) bool
+}
+
+// Parser drives a pushdown automaton for one Grammar, consuming input
+// incrementally through Feed rather than requiring it all up front:
+//
+//	p := pushdown.NewParser(grammar)
+//	p.Feed(chunk)
+//	...
+//	root, err := p.Finish()
+//
+// This lets bytes be delivered as they arrive from stdin, a socket,
+// or any io.Reader, without buffering the whole input. Each Parser
+// owns its own stack and position, so multiple parsers can run
+// concurrently over the same Grammar.
+type Parser struct {
+	grammar  Grammar
+	stack    []*token
+	astStack []*astFrame
+	trace    io.Writer
+	traced   bool
+
+	table      Table
+	scanner    *Scanner
+	recovering bool
+	errs       ParseErrorList
+	follow     map[string]map[string]bool
+
+	buf    []byte
+	offset int
+	pos    Position
+}
+
+// UseTable installs an LL(1) parse table that process consults when
+// it expands a Variable, instead of deferring the choice of
+// alternative to parseUnion's trial-and-error.
+func (p *Parser) UseTable(t Table) {
+	p.table = t
+}
+
+// UseScanner replaces the Scanner Feed consults to carve raw input
+// into lexemes before handing each one to process. NewParser already
+// installs one built with NewScanner(grammar); install a different
+// one here to add skip patterns (e.g. NewScanner(grammar, Regex(`\s+`))
+// to drop whitespace) or to match against a different alphabet.
+func (p *Parser) UseScanner(sc *Scanner) {
+	p.scanner = sc
+}
+
+// NewParser creates a Parser ready to accept input for grammar,
+// starting from grammar.Start. It installs a default Scanner built
+// from grammar's own terminals (see UseScanner), so multi-character
+// literals and Regex terminals are tokenized correctly without the
+// caller having to wire anything up by hand.
+func NewParser(grammar Grammar) *Parser {
+	p := &Parser{grammar: grammar, pos: Position{Line: 1, Column: 1}}
+	p.scanner = NewScanner(grammar)
+	p.astStack = []*astFrame{{species: grammar.Start}}
+	// The production for Start is pushed onto the stack raw, rather
+	// than resolved one layer through parseToken first: there's no
+	// real lookahead symbol yet at construction time, so doing that
+	// would mean matching against a synthetic one, which silently
+	// breaks any start production that begins directly with a
+	// Terminal or Union. Pushing it raw defers that decision to the
+	// first real call to process, which has an actual input symbol
+	// to match against — same as it does for every other symbol.
+	// Seeding it this way, rather than through process, also means
+	// there's no EndVariable marker to close for Start; it's
+	// finalized straight from astStack[0] in Finish.
+	p.push(grammar.Productions[grammar.Start])
+	return p
+}
+
+// Trace directs a log of stack transitions to w, one line per input
+// symbol consumed, mirroring the dump the original command-line
+// driver printed directly to stdout.
+func (p *Parser) Trace(w io.Writer) {
+	p.trace = w
+}
+
+func (p *Parser) writeTrace(sym string) {
+	if p.trace == nil {
+		return
+	}
+	if !p.traced {
+		fmt.Fprintf(p.trace, "- %q  %s \n", '$', reverseTokens(p.stack))
+		p.traced = true
+	}
+	fmt.Fprintf(p.trace, "%d %q  %s \n", p.offset, sym, reverseTokens(p.stack))
+}
+
+// Feed delivers the next chunk of input to the parser. It may be
+// called repeatedly as bytes arrive from stdin, a socket, or any
+// io.Reader; a lexeme the Scanner is still in the middle of matching
+// across a Feed boundary is completed rather than rejected.
+//
+// A symbol that process rejects does not stop parsing: it is recorded
+// in a ParseErrorList and the parser enters panic-mode recovery (see
+// recover), discarding input until the lookahead looks safe to resume
+// from. The same happens, without even reaching process, for a byte
+// the Scanner reports no terminal could ever start with (see
+// Scanner.incomplete) — rather than buffering it forever waiting for
+// a match that will never come, Feed records it and discards one rune
+// at a time until the Scanner recognizes something again. Feed itself
+// therefore always returns nil; accumulated errors surface from
+// Finish.
+func (p *Parser) Feed(chunk []byte) error {
+	p.buf = append(p.buf, chunk...)
+	for len(p.buf) > 0 {
+		skip, text, _, ok := p.scanner.Next(string(p.buf))
+		for _, r := range string(p.buf[:skip]) {
+			p.advance(r)
+		}
+		p.buf = p.buf[skip:]
+		if !ok {
+			if len(p.buf) == 0 || p.scanner.incomplete(string(p.buf)) {
+				break // nothing recognized at the front yet; wait for more input.
+			}
+			r, size := utf8.DecodeRuneInString(string(p.buf))
+			p.errs.add(p.pos, fmt.Errorf("no terminal matches input starting at %q", p.buf[:size]))
+			p.recovering = true
+			p.advance(r)
+			p.buf = p.buf[size:]
+			continue
+		}
+		if err := p.consume(text); err != nil {
+			return err
+		}
+		for _, r := range text {
+			p.advance(r)
+		}
+		p.buf = p.buf[len(text):]
+	}
+	return nil
+}
+
+// consume runs one lexeme the Scanner matched through panic-mode
+// recovery (if active) and process, recording a ParseError and
+// entering recovery if it's rejected.
+func (p *Parser) consume(sym string) error {
+	if p.recovering {
+		if !p.recover(sym) {
+			return nil
+		}
+		p.recovering = false
+	}
+
+	if err := p.process(sym); err != nil {
+		p.errs.add(p.pos, err)
+		p.recovering = true
+		return nil
+	}
+
+	p.writeTrace(sym)
+	p.offset++
+	return nil
+}
+
+func (p *Parser) advance(r rune) {
+	if r == '\n' {
+		p.pos.Line++
+		p.pos.Column = 1
+	} else {
+		p.pos.Column++
+	}
+}
+
+// recover looks, from the innermost non-terminal still open outward,
+// for one whose FOLLOW set or sync set (see Grammar.Sync) contains
+// sym. If it finds one, every non-terminal nested inside it (and that
+// one itself) is abandoned as a partial node, so parsing resumes
+// where its parent left off, and recover reports success.
+//
+// FOLLOW is computed straight from the grammar itself (see
+// followSets), so this works whether or not an LL(1) Table was
+// installed; if a Table is installed, its own InFollow is consulted
+// too, in case it has a reason to disagree.
+func (p *Parser) recover(sym string) bool {
+	if p.follow == nil {
+		p.follow = p.grammar.followSets()
+	}
+	for i := len(p.astStack) - 1; i >= 1; i-- {
+		name := p.astStack[i].species
+		if p.grammar.inSync(name, sym) || p.follow[name][sym] || (p.table != nil && p.table.InFollow(name, sym)) {
+			p.abandon(len(p.astStack) - i)
+			return true
+		}
+	}
+	return false
+}
+
+// closeFrame pops the innermost open astFrame and folds it into its
+// parent as a finished node — what happens whenever an EndVariable
+// marker is reached, whether process popped it off the stack itself
+// or a caller (abandon, drainEndVariables) is closing it out early.
+func (p *Parser) closeFrame() {
+	closed := p.astStack[len(p.astStack)-1]
+	p.astStack = p.astStack[:len(p.astStack)-1]
+	parent := p.astStack[len(p.astStack)-1]
+	parent.children = append(parent.children, ast.NewNode(closed.species, closed.offset, closed.children...))
+}
+
+// abandon closes the innermost count open non-terminals, folding
+// whatever partial children each gathered into its parent exactly as
+// a normal EndVariable close would, and discards every stack entry
+// down to and including their EndVariable markers.
+func (p *Parser) abandon(count int) {
+	for i := 0; i < count; i++ {
+		p.closeFrame()
+	}
+	for remaining := count; remaining > 0; {
+		t, err := p.pop()
+		if err != nil {
+			return
+		}
+		if t.kind == EndVariable {
+			remaining--
+		}
+	}
+}
+
+// drainEndVariables closes every EndVariable marker left on top of
+// the stack once input is exhausted. process only closes one when the
+// next real input symbol arrives to re-drive it — an EndVariable
+// never actually consumes that symbol, it just reacts to it — so any
+// number of them can be left stacked up with no more input left to
+// trigger their closes, even though the parse itself is complete.
+func (p *Parser) drainEndVariables() {
+	for len(p.stack) > 0 && p.stack[len(p.stack)-1].kind == EndVariable {
+		p.pop()
+		p.closeFrame()
+	}
+}
+
+// Finish signals that no more input is coming. It returns the parsed
+// syntax tree if the input was fully and validly consumed. Otherwise
+// it returns a *ParseErrorList with every error recovered from during
+// the parse, plus, if parsing never came back into sync, a final entry
+// for whatever was left unresolved.
+func (p *Parser) Finish() (ast.Node, error) {
+	if len(p.buf) > 0 {
+		p.errs.add(p.pos, fmt.Errorf("no terminal matches remaining input (%q)", p.buf))
+	}
+	p.drainEndVariables()
+	if len(p.stack) != 0 {
+		p.errs.add(p.pos, fmt.Errorf("unexpected end of input, stack not empty"))
+	}
+	if len(p.errs.Errors) > 0 {
+		return nil, &p.errs
+	}
+	return ast.NewNode(p.grammar.Start, 0, p.astStack[0].children...), nil
+}
+
+func (p *Parser) pop() (*token, error) {
+	if len(p.stack) == 0 {
+		return nil, fmt.Errorf("stack empty")
+	}
+	L := len(p.stack) - 1
+	t := p.stack[L]
+	p.stack = p.stack[:L]
+	return t, nil
+}
+
+func (p *Parser) push(t ...*token) {
+	t = reverseTokens(t)
+	p.stack = append(p.stack, t...)
+}
+
+// process always pops a symbol from the stack.  This token is
+// examined alongside the input symbol to decide what kind of
+// transition will happen.  If the transition results in values that
+// need to be pushed to the stack, this will push them.  It's like a
+// "stack manager" method.
+func (p *Parser) process(a string) error {
+	X, err := p.pop()
+	if err != nil {
+		return err
+	}
+
+	// check for the special stack token "EndVar", which only
+	// exists in the stack language. This transition is unlike
+	// others, because it does NOT consume an input token.  To
+	// achieve this effect, call process again, using the same
+	// input.
+	if X.kind == EndVariable {
+		p.closeFrame()
+		return p.process(a)
+	}
+
+	results, err := p.parseToken(a, X)
+	if err != nil {
+		return err
+	}
+	p.push(results...)
+
+	return nil
+}
+
+func (p *Parser) parseToken(s string, t *token) ([]*token, error) {
+	switch t.kind {
+	case Terminal:
+		return p.parseTerminal(s, t)
+	case Union:
+		return p.parseUnion(s, t)
+	case Concat:
+		return p.parseConcat(s, t)
+	case Variable:
+		return p.parseVariable(s, t)
+	default:
+		panic(fmt.Sprintf("unknown token kind(%v)", t.kind))
+	}
+}
+
+func (p *Parser) parseTerminal(s string, t *token) ([]*token, error) {
+	if !t.matches(s) {
+		return nil, fmt.Errorf("invalid symbol(%q)", s)
+	}
+	p.appendLeaf(s)
+	return nil, nil
+}
+
+func (p *Parser) appendLeaf(s string) {
+	top := p.astStack[len(p.astStack)-1]
+	top.children = append(top.children, ast.NewLeaf("Terminal", s, p.offset))
+}
+
+// In a concat, we only need to match the first token.  If the match
+// is successful, the remaining tokens are returned to be pushed onto
+// the stack.
+func (p *Parser) parseConcat(s string, t *token) ([]*token, error) {
+	// beware of infinite loops caused by the grammar definition.
+	result, err := p.parseToken(s, t.list[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(t.list) > 1 {
+		result = append(result, t.list[1:]...)
+	}
+	return result, nil
+}
+
+// parsing a union looks through all of the possibilities, and does
+// not push any symbols.  Returns an error only if ALL alternatives
+// return an error.
+func (p *Parser) parseUnion(s string, t *token) ([]*token, error) {
+	for _, tok := range t.list {
+		result, err := p.parseToken(s, tok)
+		if err == nil {
+			return result, nil
+		}
+	}
+	// No matches found?! We aren't in the right context!
+	return nil, fmt.Errorf("symbol(%s) not expected in token:(%v)", s, t)
+}
+
+// parseVariable expands t's production and immediately tries to match
+// s against it, the same way process does when it pops a Variable
+// straight off the stack — this is what lets a Variable nested inside
+// a Concat or Union (e.g. the head of "A = B, C ;") actually check s
+// against its own table entry or FIRST set right away, instead of
+// being pushed back unresolved and checked a symbol too late against
+// whatever lookahead happens to be current the next time it's popped.
+//
+// The returned tokens are body's own results followed by an
+// EndVariable marker, so that marker is only reached once body is
+// fully consumed, closing this frame at the right point even when
+// body is itself nested inside a larger Concat or Union.
+func (p *Parser) parseVariable(s string, t *token) ([]*token, error) {
+	name := t.data
+	body := p.grammar.Productions[name]
+	if p.table != nil {
+		alt, ok := p.table.Lookup(name, s)
+		if !ok {
+			return nil, fmt.Errorf("no production of %q for lookahead %q", name, s)
+		}
+		body = alt.(*token)
+	}
+
+	p.astStack = append(p.astStack, &astFrame{species: name, offset: p.offset})
+	results, err := p.parseToken(s, body)
+	if err != nil {
+		p.astStack = p.astStack[:len(p.astStack)-1]
+		return nil, err
+	}
+	return append(results, &token{kind: EndVariable, data: name}), nil
+}
+
+// reverseTokens returns a with its order reversed; used both to
+// restore LIFO order when pushing, and to print the stack bottom to
+// top for Trace.
+func reverseTokens(a []*token) []*token {
+	var rev []*token
+	for _, t := range a {
+		rev = append([]*token{t}, rev...)
+	}
+	return rev
+}
@@ -0,0 +1,191 @@
+package pushdown_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fractalbach/pushdown/ll1"
+	"github.com/fractalbach/pushdown/pushdown"
+)
+
+// exampleGrammar is the grammar from the package's command-line demo:
+// varA matches a run of n '0's, then a '2', then n '1's, and
+// varB matches a run of '1's followed by varB, or a '3' followed by a
+// varA.
+var exampleGrammar = []byte(`
+$ = varA, varB ;
+varA = '0', varA, '1' | '2' ;
+varB = '1', varB | '3', varA ;
+`)
+
+func TestParserAcceptsValidInput(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF(exampleGrammar)
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("021300211")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	root, err := p.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if root == nil {
+		t.Fatal("Finish returned a nil root for valid input")
+	}
+	if root.Species() != grammar.Start {
+		t.Errorf("root.Species() = %q, want %q", root.Species(), grammar.Start)
+	}
+	if len(root.Children()) == 0 {
+		t.Error("root has no children, want varA and varB")
+	}
+}
+
+// TestParserFinishDrainsEndVariables exercises a grammar whose start
+// production expands through nested non-terminals (A and B) with
+// nothing left to consume once the last terminal is matched, so their
+// EndVariable markers are still on the stack when Finish runs. A
+// Finish that doesn't drain them first would report the stack as
+// non-empty even though the input was fully and validly consumed.
+func TestParserFinishDrainsEndVariables(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF([]byte(`
+		$ = A, B ;
+		A = '2' ;
+		B = '3' ;
+	`))
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("23")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	root, err := p.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if root == nil {
+		t.Fatal("Finish returned a nil root for valid input")
+	}
+	if len(root.Children()) != 2 {
+		t.Fatalf("root has %d children, want 2 (A and B)", len(root.Children()))
+	}
+	if root.Children()[0].Species() != "A" || root.Children()[1].Species() != "B" {
+		t.Errorf("root children = %q, %q, want \"A\", \"B\"", root.Children()[0].Species(), root.Children()[1].Species())
+	}
+}
+
+// TestParserRejectsBadLeadingSymbol mutates the leading character of
+// a valid input, one that only varA's two alternatives ('0' or '2')
+// may legally begin with, to a character outside FIRST(varA). Both
+// mutations start the concat "$ = varA, varB" with a Variable
+// (varA), so this also regression-tests that process checks the
+// lookahead against varA's own table/FIRST set immediately, rather
+// than deferring the check past the point where parseUnion or
+// parseConcat already committed to accepting it.
+func TestParserRejectsBadLeadingSymbol(t *testing.T) {
+	for _, input := range []string{"121300211", "321300211"} {
+		grammar, err := pushdown.ParseEBNF(exampleGrammar)
+		if err != nil {
+			t.Fatalf("ParseEBNF: %v", err)
+		}
+		table, err := ll1.BuildTable(grammar)
+		if err != nil {
+			t.Fatalf("BuildTable: %v", err)
+		}
+
+		for _, useTable := range []bool{false, true} {
+			p := pushdown.NewParser(grammar)
+			if useTable {
+				p.UseTable(table)
+			}
+			if err := p.Feed([]byte(input)); err != nil {
+				t.Fatalf("Feed(%q): %v", input, err)
+			}
+			if _, err := p.Finish(); err == nil {
+				t.Errorf("Finish(%q) (useTable=%v): got nil error, want a *ParseErrorList (leading symbol isn't in FIRST(varA))", input, useTable)
+			}
+		}
+	}
+}
+
+// TestParserReportsUnmatchableByteImmediately feeds a byte that no
+// terminal in the grammar's alphabet could ever start with (as opposed
+// to a merely-incomplete multi-character lexeme). Feed should report it
+// right away via the ParseErrorList instead of buffering it forever
+// waiting for a match that will never come.
+func TestParserReportsUnmatchableByteImmediately(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF([]byte(`$ = 'a' ;`))
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("xa")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	_, err = p.Finish()
+	if err == nil {
+		t.Fatal("Finish: got nil error, want a *ParseErrorList reporting the unmatched 'x'")
+	}
+	list, ok := err.(*pushdown.ParseErrorList)
+	if !ok {
+		t.Fatalf("Finish: error has type %T, want *pushdown.ParseErrorList", err)
+	}
+	if len(list.Errors) == 0 {
+		t.Fatal("ParseErrorList has no errors, want at least one reporting the unmatched 'x'")
+	}
+	if !strings.Contains(list.Errors[0].Error(), `"x"`) {
+		t.Errorf("first error = %q, want it to mention the unmatched byte %q", list.Errors[0].Error(), "x")
+	}
+}
+
+// TestParserRecoversPastUnmatchableByte checks that an unmatchable byte
+// is reported as its own diagnostic and doesn't prevent panic-mode
+// recovery from picking the parse back up: 'z' belongs to neither A nor
+// B, but B still gets to consume the 'b' that follows it once recovery
+// resyncs against FOLLOW(A).
+func TestParserRecoversPastUnmatchableByte(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF([]byte(`
+		$ = A, B ;
+		A = 'a' ;
+		B = 'b' ;
+	`))
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("azb")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	_, err = p.Finish()
+	list, ok := err.(*pushdown.ParseErrorList)
+	if !ok {
+		t.Fatalf("Finish: error has type %T, want *pushdown.ParseErrorList", err)
+	}
+	if len(list.Errors) != 1 {
+		t.Fatalf("ParseErrorList has %d errors, want exactly 1 (the unmatched 'z'; 'b' should still parse via recovery)", len(list.Errors))
+	}
+	if !strings.Contains(list.Errors[0].Error(), `"z"`) {
+		t.Errorf("error = %q, want it to mention the unmatched byte %q", list.Errors[0].Error(), "z")
+	}
+}
+
+func TestParserRejectsInvalidInput(t *testing.T) {
+	grammar, err := pushdown.ParseEBNF(exampleGrammar)
+	if err != nil {
+		t.Fatalf("ParseEBNF: %v", err)
+	}
+
+	p := pushdown.NewParser(grammar)
+	if err := p.Feed([]byte("029")); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+	if _, err := p.Finish(); err == nil {
+		t.Fatal("Finish: got nil error for invalid input, want a *ParseErrorList")
+	}
+}
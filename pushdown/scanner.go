@@ -0,0 +1,81 @@
+package pushdown
+
+// Scanner turns raw input into a stream of lexemes by greedily
+// matching, at every position, the single longest terminal in its
+// alphabet — literal, TermRange, or Regex — that fits what's there.
+// It sits between the raw bytes Parser.Feed receives and process, so
+// a grammar's terminals can be whole token classes instead of single
+// characters, and so whitespace and comments can be dropped without
+// the grammar having to encode them in every production.
+type Scanner struct {
+	alphabet []*token
+	skip     []*token
+}
+
+// NewScanner builds a Scanner over every terminal reachable from
+// grammar's productions. skip names additional terminals, typically
+// built with Regex, whose matches are discarded instead of being
+// handed to the parser, e.g. NewScanner(g, Regex(`\s+`)).
+func NewScanner(grammar Grammar, skip ...*token) *Scanner {
+	return &Scanner{alphabet: grammar.terminals(), skip: skip}
+}
+
+// Next scans forward from the start of s, discarding any number of
+// leading skip matches, then matching the longest terminal that fits
+// what's left. skip reports how many leading bytes of s were consumed
+// as skip matches; when ok is true, text and term describe the
+// lexeme found just after them, and the caller should advance past
+// skip+len(text) in total.
+//
+// ok is false only when no terminal matches what's left after
+// skipping: mid-stream, Feed treats that as "wait for more input"
+// rather than a lexical error, since more bytes could still complete
+// a match (e.g. a Regex terminal that hasn't seen its full lexeme
+// yet); at Finish, leftover unmatched input is reported as an error.
+func (sc *Scanner) Next(s string) (skip int, text string, term *token, ok bool) {
+	for {
+		_, n := longestIn(sc.skip, s[skip:])
+		if n == 0 {
+			break
+		}
+		skip += n
+	}
+	term, n := longestIn(sc.alphabet, s[skip:])
+	if term == nil {
+		return skip, "", nil, false
+	}
+	return skip, s[skip : skip+n], term, true
+}
+
+// incomplete reports whether s might still become a match for some
+// terminal or skip pattern in sc given more input — Feed uses this,
+// once Next has already reported no complete match, to tell "just
+// keep buffering" apart from "the front of s will never match
+// anything in this grammar's alphabet".
+func (sc *Scanner) incomplete(s string) bool {
+	for _, t := range sc.alphabet {
+		if t.couldExtend(s) {
+			return true
+		}
+	}
+	for _, t := range sc.skip {
+		if t.couldExtend(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// longestIn returns the token in tokens that matches the longest
+// prefix of s, and the length of that match. It returns (nil, 0) if
+// none of tokens match at all.
+func longestIn(tokens []*token, s string) (*token, int) {
+	var best *token
+	var bestLen int
+	for _, t := range tokens {
+		if n, ok := t.matchPrefix(s); ok && n > bestLen {
+			best, bestLen = t, n
+		}
+	}
+	return best, bestLen
+}
@@ -0,0 +1,204 @@
+/*
+Package pushdown converts an EBNF grammar into a pushdown automaton
+and drives it over input to produce a syntax tree (see package ast).
+Grammars are loaded with ParseEBNF; input is then fed to a Parser
+incrementally, so it can come from stdin, a socket, or any io.Reader
+without being buffered up front.
+*/
+package pushdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is used to quickly identify the token, and decide how it
+// should be processed.  It's like a "token descriptor".
+type tokenKind int
+
+const (
+	Terminal tokenKind = iota
+	Concat
+	Union
+	Variable
+	EndVariable
+)
+
+// the data string of a token will depend on the kind of token it is.
+// For example, Terminals will use the data string literally, and
+// variables will use data string for the name.
+type token struct {
+	kind         tokenKind
+	list         []*token
+	data, output string
+
+	// rangeHi is set for terminals built with TermRange, in which
+	// case the terminal matches any input symbol between data and
+	// rangeHi inclusive, instead of requiring an exact match.
+	rangeHi string
+
+	// re is set for terminals built with Regex, in which case the
+	// terminal matches whatever prefix of the input satisfies the
+	// pattern (data holds the original pattern text, for String).
+	re *regexp.Regexp
+}
+
+func (t *token) String() string {
+	return t.data
+}
+
+// Symbol is read-only access to a grammar symbol's shape: its kind,
+// its literal data (a terminal's text or a variable's name), and its
+// sub-symbols (a Concat's sequence or a Union's alternatives). It lets
+// analysis packages such as ll1 walk a Grammar without depending on
+// pushdown's internal token representation.
+type Symbol interface {
+	Kind() tokenKind
+	Data() string
+	List() []Symbol
+}
+
+func (t *token) Kind() tokenKind { return t.kind }
+func (t *token) Data() string    { return t.data }
+
+func (t *token) List() []Symbol {
+	if t.list == nil {
+		return nil
+	}
+	out := make([]Symbol, len(t.list))
+	for i, c := range t.list {
+		out[i] = c
+	}
+	return out
+}
+
+// Term creates a new terminal token.
+func Term(data string) *token {
+	return &token{
+		kind: Terminal,
+		data: data,
+	}
+}
+
+// TermRange creates a terminal token that matches any single input
+// symbol lexically between lo and hi, inclusive (e.g. "a" ... "z").
+func TermRange(lo, hi string) *token {
+	return &token{
+		kind:    Terminal,
+		data:    lo,
+		rangeHi: hi,
+	}
+}
+
+// Regex creates a terminal token that matches the longest prefix of
+// input satisfying pattern (as accepted by package regexp), instead
+// of an exact literal or a single character in a TermRange. It's
+// meant to be matched by a Scanner rather than fed rune-by-rune, so a
+// grammar can write Var("Ident") = Regex("[A-Za-z_][A-Za-z0-9_]*")
+// instead of enumerating every character combination by hand.
+//
+// ParseEBNF reaches a terminal built this way through a "? ... ?"
+// special sequence (see ebnfSpecial) rather than calling Regex
+// directly, so a grammar loaded from text can use one too.
+func Regex(pattern string) *token {
+	t, err := regexTerm(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// regexTerm is Regex's logic without the panic, so callers that parse
+// a pattern from untrusted text (ParseEBNF) can report a compile
+// error instead of crashing on a malformed grammar.
+func regexTerm(pattern string) (*token, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")")
+	if err != nil {
+		return nil, err
+	}
+	return &token{kind: Terminal, data: pattern, re: re}, nil
+}
+
+// matchPrefix reports how many bytes at the start of s this terminal
+// matches, and whether it matched at all: a literal must appear in
+// full at the start of s, TermRange matches exactly one rune, and Regex matches however
+// much of s its pattern consumes, anchored at the start.
+func (t *token) matchPrefix(s string) (n int, ok bool) {
+	switch {
+	case t.re != nil:
+		loc := t.re.FindStringIndex(s)
+		if loc == nil {
+			return 0, false
+		}
+		return loc[1], true
+	case t.rangeHi != "":
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || string(r) < t.data || string(r) > t.rangeHi {
+			return 0, false
+		}
+		return size, true
+	default:
+		if !strings.HasPrefix(s, t.data) {
+			return 0, false
+		}
+		return len(t.data), true
+	}
+}
+
+// matches reports whether s, taken as a whole, satisfies this
+// terminal — used by parseTerminal, where a lexeme the Scanner
+// produced is always either accepted outright or rejected.
+func (t *token) matches(s string) bool {
+	n, ok := t.matchPrefix(s)
+	return ok && n == len(s)
+}
+
+// couldExtend reports whether s, which matchPrefix has already
+// rejected outright, might still become a match for t if more input
+// arrives — as opposed to being unmatchable no matter what follows.
+// Scanner.incomplete uses this to tell Feed whether to keep buffering or to
+// report the front of s as bad input right away.
+func (t *token) couldExtend(s string) bool {
+	switch {
+	case t.re != nil:
+		// package regexp exposes no way to ask "could some longer
+		// string still satisfy this pattern", so assume yes rather
+		// than risk misreporting a merely-incomplete lexeme (e.g. a
+		// Regex terminal mid-match across a Feed boundary) as
+		// unrecoverable.
+		return true
+	case t.rangeHi != "":
+		// TermRange matches exactly one rune with no partial state:
+		// once s holds one, matchPrefix has already decided it.
+		return len(s) == 0
+	default:
+		return len(s) < len(t.data) && strings.HasPrefix(t.data, s)
+	}
+}
+
+// And creates a concatenation of multiple tokens.
+func And(tokens ...*token) *token {
+	return &token{
+		kind: Concat,
+		list: tokens,
+	}
+}
+
+// Or creates a union of multiple tokens.
+func Or(tokens ...*token) *token {
+	return &token{
+		kind: Union,
+		list: tokens,
+	}
+}
+
+// Var creates a new variable token.  The name should match one of the
+// rules in the defined grammar.
+func Var(name string) *token {
+	return &token{
+		kind:   Variable,
+		data:   name,
+		output: "<" + name + ">",
+	}
+}